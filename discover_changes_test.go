@@ -0,0 +1,108 @@
+package argsini_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/thrawn01/args"
+	"github.com/thrawn01/argsini"
+)
+
+var _ = Describe("INIBackend.Watch / discoverChanges", func() {
+	var dir, path string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "argsini-discover")
+		Expect(err).To(BeNil())
+		path = filepath.Join(dir, "app.ini")
+		Expect(ioutil.WriteFile(path, []byte("[database]\nhost=local\nport=5432\n"), 0644)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	collect := func(events <-chan *args.ChangeEvent, timeout time.Duration) []*args.ChangeEvent {
+		var received []*args.ChangeEvent
+		deadline := time.After(timeout)
+		for {
+			select {
+			case event := <-events:
+				received = append(received, event)
+			case <-deadline:
+				return received
+			}
+		}
+	}
+
+	It("Should emit the expected sequence of change events across write, rename, truncate, and grow", func() {
+		f, err := os.Open(path)
+		Expect(err).To(BeNil())
+		defer f.Close()
+
+		backend := argsini.NewFromFile(*f, "")
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		events := backend.Watch(ctx, args.Key{})
+
+		// write: change an existing value
+		Expect(ioutil.WriteFile(path, []byte("[database]\nhost=remote\nport=5432\n"), 0644)).To(Succeed())
+		changed := collect(events, 2*time.Second)
+		Expect(hasChange(changed, "database", "host", "remote", false)).To(BeTrue())
+
+		// rename: atomic replace via a sibling tempfile, as editors do
+		tmp := filepath.Join(dir, "app.ini.tmp")
+		Expect(ioutil.WriteFile(tmp, []byte("[database]\nhost=remote\nport=5432\nextra=added\n"), 0644)).To(Succeed())
+		Expect(os.Rename(tmp, path)).To(Succeed())
+		added := collect(events, 2*time.Second)
+		Expect(hasChange(added, "database", "extra", "added", false)).To(BeTrue())
+
+		// truncate: delete a key by shrinking the file
+		Expect(ioutil.WriteFile(path, []byte("[database]\nhost=remote\n"), 0644)).To(Succeed())
+		deleted := collect(events, 2*time.Second)
+		Expect(hasChange(deleted, "database", "port", "", true) || hasChange(deleted, "database", "extra", "", true)).To(BeTrue())
+
+		// grow: add a brand new section/key
+		Expect(ioutil.WriteFile(path, []byte("[database]\nhost=remote\n\n[cache]\nttl=60\n"), 0644)).To(Succeed())
+		grown := collect(events, 2*time.Second)
+		Expect(hasChange(grown, "cache", "ttl", "60", false)).To(BeTrue())
+	})
+
+	It("Should report every key that changed in the same write, not just one with a stale duplicate", func() {
+		f, err := os.Open(path)
+		Expect(err).To(BeNil())
+		defer f.Close()
+
+		backend := argsini.NewFromFile(*f, "")
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		events := backend.Watch(ctx, args.Key{})
+
+		// Change both "host" and "port" in the same write, so discoverChanges
+		// returns more than one ChangeEvent from a single call.
+		Expect(ioutil.WriteFile(path, []byte("[database]\nhost=remote\nport=6543\n"), 0644)).To(Succeed())
+		changed := collect(events, 2*time.Second)
+
+		Expect(hasChange(changed, "database", "host", "remote", false)).To(BeTrue())
+		Expect(hasChange(changed, "database", "port", "6543", false)).To(BeTrue())
+	})
+})
+
+func hasChange(events []*args.ChangeEvent, group, name, value string, deleted bool) bool {
+	for _, event := range events {
+		if event.Key.Group == group && event.Key.Name == name && event.Deleted == deleted {
+			if deleted || string(event.Value) == value {
+				return true
+			}
+		}
+	}
+	return false
+}
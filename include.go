@@ -0,0 +1,99 @@
+package argsini
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// includeKey is the reserved key name that pulls another ini file's
+// sections into the document it appears in, e.g. `include = conf.d/*.ini`.
+const includeKey = "include"
+
+// resolveIncludes walks doc looking for `include` keys, loads the files (or
+// glob matches) they name relative to baseDir, and merges each into doc with
+// later includes overriding earlier ones and earlier file content. visited
+// tracks the absolute paths already being resolved so an include cycle is
+// reported instead of recursing forever.
+func resolveIncludes(doc *Document, baseDir string, visited map[string]bool) error {
+	for _, sectionName := range doc.Sections() {
+		for _, pair := range doc.Get(sectionName, includeKey) {
+			paths, err := expandIncludePattern(baseDir, pair.Value)
+			if err != nil {
+				return err
+			}
+			for _, path := range paths {
+				if err := mergeInclude(doc, sectionName, path, visited); err != nil {
+					return err
+				}
+			}
+		}
+		doc.Delete(sectionName, includeKey)
+	}
+	return nil
+}
+
+// expandIncludePattern resolves an include value relative to baseDir and
+// expands any glob it contains, returning matches in a deterministic order.
+func expandIncludePattern(baseDir, pattern string) ([]string, error) {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(baseDir, pattern)
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, errors.Wrapf(err, "while expanding include pattern '%s'", pattern)
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// mergeInclude loads path, resolves any includes it has in turn, and merges
+// its sections into doc. Values from path win over whatever doc already has,
+// since later includes take precedence over earlier ones. A key is cleared
+// only once per include file and then has every one of its values appended,
+// so a key repeated within the included file keeps all of its values instead
+// of collapsing to the last one.
+func mergeInclude(doc *Document, sectionName, path string, visited map[string]bool) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return errors.Wrapf(err, "while resolving include '%s'", path)
+	}
+	if visited[abs] {
+		return errors.Errorf("include cycle detected at '%s'", abs)
+	}
+
+	data, err := ioutil.ReadFile(abs)
+	if err != nil {
+		return errors.Wrapf(err, "while reading include '%s'", abs)
+	}
+
+	included, err := ParseDocument(bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrapf(err, "while parsing include '%s'", abs)
+	}
+
+	visited[abs] = true
+	defer delete(visited, abs)
+
+	if err := resolveIncludes(included, filepath.Dir(abs), visited); err != nil {
+		return err
+	}
+
+	cleared := make(map[string]map[string]bool)
+	for _, includedSection := range included.Sections() {
+		if cleared[includedSection] == nil {
+			cleared[includedSection] = make(map[string]bool)
+		}
+		for _, pair := range included.List(includedSection) {
+			if !cleared[includedSection][pair.Key] {
+				doc.Delete(includedSection, pair.Key)
+				cleared[includedSection][pair.Key] = true
+			}
+			doc.getOrCreateSection(includedSection).add(&Pair{Key: pair.Key, Value: pair.Value})
+		}
+	}
+	return nil
+}
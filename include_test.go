@@ -0,0 +1,90 @@
+package argsini_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/thrawn01/args"
+	"github.com/thrawn01/argsini"
+)
+
+var _ = Describe("include directives", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "argsini-include")
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("Should merge an included file, with the include overriding the base file", func() {
+		Expect(ioutil.WriteFile(filepath.Join(dir, "extra.ini"),
+			[]byte("[database]\nhost=remote\n"), 0644)).To(Succeed())
+
+		main := filepath.Join(dir, "app.ini")
+		Expect(ioutil.WriteFile(main,
+			[]byte("[database]\nhost=local\nport=5432\ninclude=extra.ini\n"), 0644)).To(Succeed())
+
+		f, err := os.Open(main)
+		Expect(err).To(BeNil())
+		defer f.Close()
+
+		backend := argsini.NewFromFile(*f, "")
+		pair, err := backend.Get(context.Background(), args.Key{Group: "database", Name: "host"})
+		Expect(err).To(BeNil())
+		Expect(string(pair.Value)).To(Equal("remote"))
+
+		pair, err = backend.Get(context.Background(), args.Key{Group: "database", Name: "port"})
+		Expect(err).To(BeNil())
+		Expect(string(pair.Value)).To(Equal("5432"))
+	})
+
+	It("Should keep every value of a key repeated within an included file", func() {
+		Expect(ioutil.WriteFile(filepath.Join(dir, "extra.ini"),
+			[]byte("[servers]\nhost=one.example.com\nhost=two.example.com\n"), 0644)).To(Succeed())
+
+		main := filepath.Join(dir, "app.ini")
+		Expect(ioutil.WriteFile(main,
+			[]byte("[servers]\nhost=local\ninclude=extra.ini\n"), 0644)).To(Succeed())
+
+		f, err := os.Open(main)
+		Expect(err).To(BeNil())
+		defer f.Close()
+
+		backend := argsini.NewFromFile(*f, "")
+		pairs, err := backend.List(context.Background(), args.Key{})
+		Expect(err).To(BeNil())
+
+		var hosts []string
+		for _, pair := range pairs {
+			if pair.Key.Group == "servers" && pair.Key.Name == "host" {
+				hosts = append(hosts, string(pair.Value))
+			}
+		}
+		Expect(hosts).To(Equal([]string{"one.example.com", "two.example.com"}))
+	})
+
+	It("Should detect include cycles", func() {
+		a := filepath.Join(dir, "a.ini")
+		b := filepath.Join(dir, "b.ini")
+		Expect(ioutil.WriteFile(a, []byte("include=b.ini\n"), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(b, []byte("include=a.ini\n"), 0644)).To(Succeed())
+
+		f, err := os.Open(a)
+		Expect(err).To(BeNil())
+		defer f.Close()
+
+		backend := argsini.NewFromFile(*f, "")
+		_, err = backend.Get(context.Background(), args.Key{Group: "", Name: "anything"})
+		Expect(err).To(Not(BeNil()))
+		Expect(err.Error()).To(ContainSubstring("cycle"))
+	})
+})
@@ -1,17 +1,28 @@
 package argsini
 
 import (
+	"bytes"
 	"context"
+	"hash/fnv"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/go-ini/ini"
 	"github.com/pkg/errors"
 	"github.com/thrawn01/args"
 )
 
+// snapshotEntry is the last known hash and value of a single key, used by
+// discoverChanges to tell whether a key changed without re-comparing every
+// byte of every value on each tick.
+type snapshotEntry struct {
+	hash  uint64
+	value []byte
+}
+
 var KeySeparator string = "/"
 
 type INIBackend struct {
@@ -20,9 +31,20 @@ type INIBackend struct {
 	section    string
 	data       []byte
 	file       os.File
-	ini        *ini.File
+	doc        *Document
 	done       chan struct{}
 	wg         sync.WaitGroup
+
+	mu sync.RWMutex
+	// selfWriteEpoch counts Set/Delete writes this backend made that the
+	// Watch goroutine has not yet seen echoed back from fsnotify. Each
+	// echoed event consumes one epoch instead of producing a duplicate
+	// ChangeEvent for a change the caller already knows about.
+	selfWriteEpoch int32
+	// snapshot is the hash of every key as of the last successful load,
+	// letting discoverChanges diff old vs. new in a single pass over each
+	// map rather than re-reading every value's bytes on every tick.
+	snapshot map[args.Key]snapshotEntry
 }
 
 // Provide a file and a section to get values from. If no section is provided, key are taken from
@@ -32,7 +54,7 @@ func NewFromFile(file os.File, section string) args.Backend {
 		file:     file,
 		fileName: file.Name(),
 		section:  section,
-	}, nil
+	}
 }
 
 func NewFromBuffer(data []byte, fileName, section string) args.Backend {
@@ -40,75 +62,189 @@ func NewFromBuffer(data []byte, fileName, section string) args.Backend {
 		data:     data,
 		fileName: fileName,
 		section:  section,
-	}, nil
+	}
+}
+
+// ensureLoaded parses the backing file or buffer on first use. Subsequent
+// calls are a no-op once self.doc is populated.
+func (self *INIBackend) ensureLoaded() error {
+	self.mu.RLock()
+	loaded := self.doc != nil
+	self.mu.RUnlock()
+	if loaded {
+		return nil
+	}
+
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if self.doc != nil {
+		return nil
+	}
+	return self.loadINI()
 }
 
+// loadINI parses self.data into self.doc. Callers must hold self.mu.
 func (self *INIBackend) loadINI() (err error) {
 	if len(self.data) == 0 {
-		self.data, err = ioutil.ReadAll(self.file)
+		self.data, err = ioutil.ReadAll(&self.file)
 		if err != nil {
-			return errors.Wrapf(err, "while reading ini '%s'", self.fileName())
+			return errors.Wrapf(err, "while reading ini '%s'", self.fileName)
 		}
 	}
-	if self.ini == nil {
-		self.ini, err = ini.Load(self.data)
+	self.doc, err = ParseDocument(bytes.NewReader(self.data))
+	if err != nil {
+		return errors.Wrapf(err, "while parsing ini '%s'", self.fileName)
+	}
+
+	visited := make(map[string]bool)
+	if abs, err := filepath.Abs(self.fileName); err == nil {
+		visited[abs] = true
 	}
-	return err
+	if err := resolveIncludes(self.doc, filepath.Dir(self.fileName), visited); err != nil {
+		return errors.Wrapf(err, "while resolving includes for '%s'", self.fileName)
+	}
+	self.snapshot = takeSnapshot(self.doc)
+	return nil
 }
 
 // Get retrieves a value specified by the key
 func (self *INIBackend) Get(ctx context.Context, key args.Key) (args.Pair, error) {
-
-	if err := self.loadINI(); err != nil {
+	if err := self.ensureLoaded(); err != nil {
 		return args.Pair{}, err
 	}
 
-	if self.section != "" {
-		group := self.ini.Section(self.section)
-		if group == nil {
-			return args.Pair{}, errors.Errorf("non-existant section '%s'", self.section)
-		}
-		return args.Pair{
-			Key:   key,
-			Value: []byte(group.Key(key.Join(KeySeparator)).Value()),
-		}, nil
-	}
-	group := self.ini.Section(key.Group)
-	if group == nil {
-		return args.Pair{}, errors.Errorf("non-existant section '%s'", self.section)
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+
+	sectionName, name := self.sectionAndName(key)
+	pairs := self.doc.Get(sectionName, name)
+	if len(pairs) == 0 {
+		return args.Pair{Key: key}, nil
 	}
-	return args.Pair{
-		Key:   key,
-		Value: []byte(group.Key(key.Name).Value()),
-	}, nil
+	return args.Pair{Key: key, Value: []byte(pairs[0].Value)}, nil
 }
 
 // List retrieves all keys and values under a provided group
 func (self *INIBackend) List(ctx context.Context, key args.Key) ([]args.Pair, error) {
-	var results []args.Pair
-
-	if err := self.loadINI(); err != nil {
-		return args.Pair{}, err
+	if err := self.ensureLoaded(); err != nil {
+		return nil, err
 	}
 
-	for _, section := range self.ini.Sections() {
-		group := self.ini.Section(section.Name())
-		for _, key := range group.KeyStrings() {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+
+	var results []args.Pair
+	for _, sectionName := range self.doc.Sections() {
+		for _, pair := range self.doc.List(sectionName) {
 			results = append(results, args.Pair{
 				Key: args.Key{
-					Group: section.Name(),
-					Name:  key,
+					Group: sectionName,
+					Name:  pair.Key,
 				},
-				Value: []byte(group.Key(key).Value()),
+				Value: []byte(pair.Value),
 			})
 		}
 	}
 	return results, nil
 }
 
-// Set the provided key to value.
+// Set the provided key to value, persisting the change to fileName
+// atomically and notifying any active Watch of the update.
 func (self *INIBackend) Set(ctx context.Context, key args.Key, value []byte) error {
-	return errors.New("Not Implemented")
+	if err := self.ensureLoaded(); err != nil {
+		return err
+	}
+
+	self.mu.Lock()
+	sectionName, name := self.sectionAndName(key)
+	self.doc.Set(sectionName, name, string(value))
+	err := self.persist()
+	self.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	self.notify(&args.ChangeEvent{Key: key, Value: value})
+	return nil
+}
+
+// Delete removes the provided key, persisting the change the same way Set
+// does.
+func (self *INIBackend) Delete(ctx context.Context, key args.Key) error {
+	if err := self.ensureLoaded(); err != nil {
+		return err
+	}
+
+	self.mu.Lock()
+	sectionName, name := self.sectionAndName(key)
+	if !self.doc.Delete(sectionName, name) {
+		self.mu.Unlock()
+		return nil
+	}
+	err := self.persist()
+	self.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	self.notify(&args.ChangeEvent{Key: key, Deleted: true})
+	return nil
+}
+
+// persist serializes self.doc to a tempfile next to fileName, fsyncs it, and
+// renames it into place so a crash mid-write can never leave a partial or
+// corrupt file behind. Callers must hold self.mu for writing.
+func (self *INIBackend) persist() error {
+	dir := filepath.Dir(self.fileName)
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(self.fileName)+".tmp")
+	if err != nil {
+		return errors.Wrapf(err, "while creating tempfile for '%s'", self.fileName)
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+
+	if _, err := self.doc.WriteTo(tmp); err != nil {
+		tmp.Close()
+		return errors.Wrapf(err, "while writing '%s'", tmpName)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return errors.Wrapf(err, "while syncing '%s'", tmpName)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrapf(err, "while closing '%s'", tmpName)
+	}
+	if err := os.Rename(tmpName, self.fileName); err != nil {
+		return errors.Wrapf(err, "while renaming '%s' to '%s'", tmpName, self.fileName)
+	}
+
+	if data, err := ioutil.ReadFile(self.fileName); err == nil {
+		self.data = data
+	}
+	self.snapshot = takeSnapshot(self.doc)
+	// The watcher will see this as its own write reflected back through
+	// fsnotify; tell it to swallow the next echoed event.
+	atomic.AddInt32(&self.selfWriteEpoch, 1)
+	return nil
+}
+
+// notify delivers event on changeChan if a Watch is active.
+func (self *INIBackend) notify(event *args.ChangeEvent) {
+	self.mu.RLock()
+	changeChan := self.changeChan
+	self.mu.RUnlock()
+	if changeChan != nil {
+		changeChan <- event
+	}
+}
+
+// sectionAndName resolves the ini section and key name Get/List/Set should
+// operate on, preferring the fixed self.section when one was provided.
+func (self *INIBackend) sectionAndName(key args.Key) (sectionName, name string) {
+	if self.section != "" {
+		return self.section, key.Join(KeySeparator)
+	}
+	return key.Group, key.Name
 }
 
 // Watch monitors store for changes to key.
@@ -116,11 +252,18 @@ func (self *INIBackend) Watch(ctx context.Context, key args.Key) <-chan *args.Ch
 	var fileEvent chan fileEvent
 	var err error
 
+	self.mu.Lock()
 	self.changeChan = make(chan *args.ChangeEvent)
 	self.done = make(chan struct{})
+	self.mu.Unlock()
 
 	self.wg.Add(1)
 	go func() {
+		defer self.wg.Done()
+		// Closing changeChan on every exit path tells anything ranging over
+		// it (e.g. layeredBackend.Watch) that this backend is done, so its
+		// own wg.Wait() can complete instead of blocking forever.
+		defer close(self.changeChan)
 
 		for {
 			// Keep trying to watch the file until user tells us to stop
@@ -143,22 +286,43 @@ func (self *INIBackend) Watch(ctx context.Context, key args.Key) <-chan *args.Ch
 			break
 		}
 
-		defer self.wg.Done()
 		for {
 			select {
 			case _, ok := <-fileEvent:
 				if !ok {
 					return
 				}
+				// Swallow the echo of our own Set/Delete write instead of
+				// reporting it back to the caller as an external change.
+				if self.consumeSelfWrite() {
+					continue
+				}
 				for _, change := range self.discoverChanges(self.fileName) {
+					change := change
 					self.changeChan <- &change
 				}
+			case <-ctx.Done():
+				return
 			}
 		}
 	}()
 	return self.changeChan
 }
 
+// consumeSelfWrite reports whether this fsnotify event should be attributed
+// to one of our own in-flight Set/Delete writes, consuming one epoch if so.
+func (self *INIBackend) consumeSelfWrite() bool {
+	for {
+		n := atomic.LoadInt32(&self.selfWriteEpoch)
+		if n <= 0 {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&self.selfWriteEpoch, n, n-1) {
+			return true
+		}
+	}
+}
+
 // Return the root key used to store all other keys in the backend
 func (self *INIBackend) GetRootKey() string {
 	return self.section
@@ -169,82 +333,78 @@ func (self *INIBackend) Close() {
 	// TODO: Cancel any watches if they exist
 }
 
-func (self *INIBackend) discoverChanges(event fileEvent) []args.ChangeEvent {
+func (self *INIBackend) discoverChanges(fileName string) []args.ChangeEvent {
 	var results []args.ChangeEvent
 
 	// Load the file and Determine what changed
-	iniFile, err := ini.Load(self.data)
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return []args.ChangeEvent{{
+			Err: errors.Wrapf(err, "while re-reading ini '%s'", fileName),
+		}}
+	}
+
+	doc, err := ParseDocument(bytes.NewReader(data))
 	if err != nil {
 		return []args.ChangeEvent{{
-			Err: err,
+			Err: errors.Wrapf(err, "while parsing ini '%s'", fileName),
+		}}
+	}
+
+	visited := make(map[string]bool)
+	if abs, err := filepath.Abs(fileName); err == nil {
+		visited[abs] = true
+	}
+	if err := resolveIncludes(doc, filepath.Dir(fileName), visited); err != nil {
+		return []args.ChangeEvent{{
+			Err: errors.Wrapf(err, "while resolving includes for '%s'", fileName),
 		}}
 	}
 
-	// For each item in the existing ini file
-	for _, lh := range pairList(self.ini) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
 
-		// Compare with the new ini file
-		rh := getPair(lh, iniFile)
-		if rh == nil {
-			// Deleted event
-			results = append(results, args.ChangeEvent{
-				Key:     lh.Key,
-				Value:   lh.Value,
-				Deleted: true,
-			})
-			continue
-		}
-		if lh.Value != rh.Value {
-			// Value updated event
-			results = append(results, args.ChangeEvent{
-				Key:   lh.Key,
-				Value: lh.Value,
-			})
+	newSnapshot := takeSnapshot(doc)
+
+	// Added or changed: present in the new snapshot, missing or hashing
+	// differently in the old one.
+	for key, next := range newSnapshot {
+		prev, existed := self.snapshot[key]
+		if !existed || prev.hash != next.hash {
+			results = append(results, args.ChangeEvent{Key: key, Value: next.value})
 		}
 	}
 
-	// For each item in the new ini file
-	for _, lh := range pairList(iniFile) {
-		// Compare with the old ini file
-		rh := getPair(lh, self.ini)
-		if rh == nil {
-			// Add event
-			results = append(results, args.ChangeEvent{
-				Key:   lh.Key,
-				Value: lh.Value,
-			})
+	// Deleted: present in the old snapshot, missing from the new one.
+	for key, prev := range self.snapshot {
+		if _, ok := newSnapshot[key]; !ok {
+			results = append(results, args.ChangeEvent{Key: key, Value: prev.value, Deleted: true})
 		}
 	}
 
-	self.ini = iniFile
+	self.data = data
+	self.doc = doc
+	self.snapshot = newSnapshot
 	return results
 }
 
-func pairList(iniFile *ini.File) []args.Pair {
-	var results []args.Pair
-	for _, section := range iniFile.Sections() {
-		group := iniFile.Section(section.Name())
-		for key, value := range group.KeysHash() {
-			results = append(results, args.Pair{
-				Key: args.Key{
-					Name:  key,
-					Group: section.Name(),
-				},
-				Value: value,
-			})
+// takeSnapshot hashes every key/value pair in doc so discoverChanges can
+// tell whether a key changed with a single uint64 comparison instead of
+// re-comparing the raw bytes of every value on every tick.
+func takeSnapshot(doc *Document) map[args.Key]snapshotEntry {
+	snapshot := make(map[args.Key]snapshotEntry)
+	for _, sectionName := range doc.Sections() {
+		for _, pair := range doc.List(sectionName) {
+			key := args.Key{Group: sectionName, Name: pair.Key}
+			value := []byte(pair.Value)
+			snapshot[key] = snapshotEntry{hash: hashValue(value), value: value}
 		}
 	}
-	return []args.Pair{}
+	return snapshot
 }
 
-func getPair(pair args.Pair, iniFile *ini.File) *args.Pair {
-	section, err := iniFile.GetSection(pair.Key.Group)
-	if err != nil {
-		return nil
-	}
-	return &args.Pair{
-		Key:   pair.Key,
-		Value: section.Key(pair.Key.Name).String(),
-	}
-	return nil
+func hashValue(value []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(value)
+	return h.Sum64()
 }
@@ -0,0 +1,59 @@
+package argsini_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/thrawn01/args"
+	"github.com/thrawn01/argsini"
+)
+
+var _ = Describe("INIBackend.Set/Delete", func() {
+	var dir, path string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "argsini-set")
+		Expect(err).To(BeNil())
+		path = filepath.Join(dir, "app.ini")
+		Expect(ioutil.WriteFile(path, []byte("[database]\nhost=local\n"), 0644)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("Should persist Set to disk", func() {
+		f, err := os.Open(path)
+		Expect(err).To(BeNil())
+		defer f.Close()
+
+		backend := argsini.NewFromFile(*f, "")
+		err = backend.Set(context.Background(), args.Key{Group: "database", Name: "host"}, []byte("remote"))
+		Expect(err).To(BeNil())
+
+		data, err := ioutil.ReadFile(path)
+		Expect(err).To(BeNil())
+		Expect(string(data)).To(ContainSubstring("host = remote"))
+	})
+
+	It("Should persist Delete to disk", func() {
+		f, err := os.Open(path)
+		Expect(err).To(BeNil())
+		defer f.Close()
+
+		backend := argsini.NewFromFile(*f, "")
+		err = backend.(interface {
+			Delete(ctx context.Context, key args.Key) error
+		}).Delete(context.Background(), args.Key{Group: "database", Name: "host"})
+		Expect(err).To(BeNil())
+
+		data, err := ioutil.ReadFile(path)
+		Expect(err).To(BeNil())
+		Expect(string(data)).To(Not(ContainSubstring("host")))
+	})
+})
@@ -0,0 +1,13 @@
+package argsini_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestArgsini(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Argsini Suite")
+}
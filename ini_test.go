@@ -2,6 +2,9 @@ package argsini_test
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -47,7 +50,7 @@ var _ = Describe("argsini", func() {
 			parser.Log(log)
 
 			input := []byte("one=this is one value\ntwo=this is two value\n")
-			opt, err := parser.FromBackend(argsini.NewFromBuffer("/tmp/fake-file.txt", input, ""))
+			opt, err := parser.FromBackend(argsini.NewFromBuffer(input, "/tmp/fake-file.txt", ""))
 			Expect(err).To(BeNil())
 			Expect(opt.String("one")).To(Equal("this is one value"))
 		})
@@ -60,7 +63,7 @@ var _ = Describe("argsini", func() {
 			cmdLine := []string{"--three", "this is three value"}
 			opt, err := parser.Parse(cmdLine)
 			input := []byte("one=this is one value\ntwo=this is two value\n")
-			opt, err = parser.FromBackend(argsini.NewFromBuffer("/tmp/fake-file.txt", input, ""))
+			opt, err = parser.FromBackend(argsini.NewFromBuffer(input, "/tmp/fake-file.txt", ""))
 			Expect(err).To(BeNil())
 			Expect(opt.String("one")).To(Equal("this is one value"))
 			Expect(opt.String("three")).To(Equal("this is three value"))
@@ -74,7 +77,7 @@ var _ = Describe("argsini", func() {
 			cmdLine := []string{"--three", "this is three value", "--one", "this is from the cmd line"}
 			opt, err := parser.Parse(cmdLine)
 			input := []byte("one=this is one value\ntwo=this is two value\n")
-			opt, err = parser.FromBackend(argsini.NewFromBuffer("/tmp/fake-file.txt", input, ""))
+			opt, err = parser.FromBackend(argsini.NewFromBuffer(input, "/tmp/fake-file.txt", ""))
 			Expect(err).To(BeNil())
 			Expect(opt.String("one")).To(Equal("this is from the cmd line"))
 			Expect(opt.String("three")).To(Equal("this is three value"))
@@ -90,7 +93,7 @@ var _ = Describe("argsini", func() {
 			Expect(list).To(Equal([]string{"foo", "bar", "bit"}))
 
 			input := []byte("list=six,five,four\n")
-			opt, err = parser.FromBackend(argsini.NewFromBuffer("/tmp/fake-file.txt", input, ""))
+			opt, err = parser.FromBackend(argsini.NewFromBuffer(input, "/tmp/fake-file.txt", ""))
 			Expect(err).To(BeNil())
 			Expect(opt.StringSlice("list")).To(Equal([]string{"six", "five", "four"}))
 			Expect(list).To(Equal([]string{"six", "five", "four"}))
@@ -99,7 +102,7 @@ var _ = Describe("argsini", func() {
 			parser := args.NewParser()
 			parser.AddConfig("one").Required()
 			input := []byte("two=this is one value\nthree=this is two value\n")
-			_, err := parser.FromBackend(argsini.NewFromBuffer("/tmp/fake-file.txt", input, ""))
+			_, err := parser.FromBackend(argsini.NewFromBuffer(input, "/tmp/fake-file.txt", ""))
 			Expect(err).To(Not(BeNil()))
 			Expect(err.Error()).To(Equal("config 'one' is required"))
 		})
@@ -115,7 +118,7 @@ var _ = Describe("argsini", func() {
 				[database]
 				debug=false
 			`)
-			opt, err = parser.FromBackend(argsini.NewFromBuffer("/tmp/fake-file.txt", iniFile, ""))
+			opt, err = parser.FromBackend(argsini.NewFromBuffer(iniFile, "/tmp/fake-file.txt", ""))
 			Expect(err).To(BeNil())
 			Expect(opt.Bool("debug")).To(Equal(true))
 			Expect(opt.Group("database").Bool("debug")).To(Equal(false))
@@ -139,7 +142,7 @@ var _ = Describe("argsini", func() {
 			Expect(opt.IsSet("five")).To(Equal(false))
 
 			input := []byte("two=this is two value\nthree=yes")
-			opt, err = parser.FromBackend(argsini.NewFromBuffer("/tmp/fake-file.txt", input, ""))
+			opt, err = parser.FromBackend(argsini.NewFromBuffer(input, "/tmp/fake-file.txt", ""))
 			Expect(err).To(BeNil())
 			Expect(opt.IsSet("two")).To(Equal(true))
 			Expect(opt.IsSet("one")).To(Equal(true))
@@ -172,7 +175,7 @@ var _ = Describe("argsini", func() {
 				fruit-snacks=100 Cals
 				m&ms=400 Cals
 			`)
-			backend := argsini.NewFromBuffer("/tmp/fake-file.txt", iniFile, "")
+			backend := argsini.NewFromBuffer(iniFile, "/tmp/fake-file.txt", "")
 			opt, err = parser.FromBackend(backend)
 			Expect(err).To(BeNil())
 			Expect(opt.Group("candy-bars").ToMap()).To(Equal(map[string]interface{}{
@@ -185,11 +188,23 @@ var _ = Describe("argsini", func() {
 	})
 	Describe("argsini.NewFromFile()", func() {
 		It("Should watch ini file for new values", func() {
+			dir, err := ioutil.TempDir("", "argsini-ini-test")
+			Expect(err).To(BeNil())
+			defer os.RemoveAll(dir)
+
+			path := filepath.Join(dir, "fake-file.txt")
+			Expect(ioutil.WriteFile(path,
+				[]byte("[endpoints]\nendpoint1=http://endpoint1.com:3366\n"), 0644)).To(Succeed())
+
+			f, err := os.Open(path)
+			Expect(err).To(BeNil())
+			defer f.Close()
+
 			parser := args.NewParser()
 			parser.Log(log)
 			parser.AddConfigGroup("endpoints")
 
-			backend := argsini.NewFromFile("/tmp/fake-file.txt", ""))
+			backend := argsini.NewFromFile(*f, "")
 			opts, err := parser.FromBackend(backend)
 
 			Expect(err).To(BeNil())
@@ -210,7 +225,11 @@ var _ = Describe("argsini", func() {
 				// Tell the test to continue, Change event was handled
 				close(done)
 			})
-			// TODO: Add a new endpoint to the ini file
+
+			// Add a new endpoint to the ini file
+			Expect(ioutil.WriteFile(path,
+				[]byte("[endpoints]\nendpoint1=http://endpoint1.com:3366\nendpoint2=http://endpoint2.com:3366\n"),
+				0644)).To(Succeed())
 
 			// Wait until the change event is handled
 			<-done
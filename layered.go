@@ -0,0 +1,114 @@
+package argsini
+
+import (
+	"context"
+	"sync"
+
+	"github.com/thrawn01/args"
+)
+
+// layeredBackend composes several backends into one, with backends later in
+// the list taking precedence over earlier ones -- the same rule argsini
+// uses for `include` directives. It lets a caller layer, for example,
+// `/etc/app.ini`, `/etc/app.d/*.ini`, and `~/.config/app.ini` as a single
+// args.Backend.
+type layeredBackend struct {
+	backends []args.Backend
+}
+
+// NewLayered composes backends into a single args.Backend. Backends passed
+// later override earlier ones for any key they both define, mirroring the
+// precedence rules of INI include directives.
+func NewLayered(backends ...args.Backend) args.Backend {
+	return &layeredBackend{backends: backends}
+}
+
+// Get returns the value from the highest-precedence backend that has one.
+func (self *layeredBackend) Get(ctx context.Context, key args.Key) (args.Pair, error) {
+	result := args.Pair{Key: key}
+	for _, backend := range self.backends {
+		pair, err := backend.Get(ctx, key)
+		if err != nil {
+			return args.Pair{}, err
+		}
+		if len(pair.Value) != 0 {
+			result = pair
+		}
+	}
+	return result, nil
+}
+
+// List returns the merged view of every backend, with later backends
+// overriding the values earlier backends set for the same key.
+func (self *layeredBackend) List(ctx context.Context, key args.Key) ([]args.Pair, error) {
+	var order []args.Key
+	merged := make(map[args.Key]args.Pair)
+
+	for _, backend := range self.backends {
+		pairs, err := backend.List(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		for _, pair := range pairs {
+			if _, ok := merged[pair.Key]; !ok {
+				order = append(order, pair.Key)
+			}
+			merged[pair.Key] = pair
+		}
+	}
+
+	results := make([]args.Pair, 0, len(order))
+	for _, k := range order {
+		results = append(results, merged[k])
+	}
+	return results, nil
+}
+
+// Set forwards the write to the highest-precedence backend, since that is
+// the layer a caller means when they ask to persist a change.
+func (self *layeredBackend) Set(ctx context.Context, key args.Key, value []byte) error {
+	if len(self.backends) == 0 {
+		return nil
+	}
+	return self.backends[len(self.backends)-1].Set(ctx, key, value)
+}
+
+// Watch subscribes to every underlying backend and multiplexes their
+// ChangeEvent streams into a single channel.
+func (self *layeredBackend) Watch(ctx context.Context, key args.Key) <-chan *args.ChangeEvent {
+	result := make(chan *args.ChangeEvent)
+
+	var wg sync.WaitGroup
+	for _, backend := range self.backends {
+		upstream := backend.Watch(ctx, key)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for event := range upstream {
+				result <- event
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(result)
+	}()
+
+	return result
+}
+
+// GetRootKey returns the root key of the highest-precedence backend.
+func (self *layeredBackend) GetRootKey() string {
+	if len(self.backends) == 0 {
+		return ""
+	}
+	return self.backends[len(self.backends)-1].GetRootKey()
+}
+
+// Close closes every underlying backend.
+func (self *layeredBackend) Close() {
+	for _, backend := range self.backends {
+		backend.Close()
+	}
+}
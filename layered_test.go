@@ -0,0 +1,62 @@
+package argsini_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/thrawn01/args"
+	"github.com/thrawn01/argsini"
+)
+
+var _ = Describe("NewLayered", func() {
+	It("Should let later backends override earlier ones", func() {
+		base := argsini.NewFromBuffer([]byte("[database]\nhost=local\nport=5432\n"), "/tmp/base.ini", "")
+		override := argsini.NewFromBuffer([]byte("[database]\nhost=remote\n"), "/tmp/override.ini", "")
+
+		layered := argsini.NewLayered(base, override)
+
+		pair, err := layered.Get(context.Background(), args.Key{Group: "database", Name: "host"})
+		Expect(err).To(BeNil())
+		Expect(string(pair.Value)).To(Equal("remote"))
+
+		pair, err = layered.Get(context.Background(), args.Key{Group: "database", Name: "port"})
+		Expect(err).To(BeNil())
+		Expect(string(pair.Value)).To(Equal("5432"))
+	})
+
+	It("Should close its channel once every backend's Watch closes theirs", func() {
+		dir, err := ioutil.TempDir("", "argsini-layered-watch")
+		Expect(err).To(BeNil())
+		defer os.RemoveAll(dir)
+
+		basePath := filepath.Join(dir, "base.ini")
+		Expect(ioutil.WriteFile(basePath, []byte("[database]\nhost=local\n"), 0644)).To(Succeed())
+		overridePath := filepath.Join(dir, "override.ini")
+		Expect(ioutil.WriteFile(overridePath, []byte("[database]\nport=5432\n"), 0644)).To(Succeed())
+
+		baseFile, err := os.Open(basePath)
+		Expect(err).To(BeNil())
+		defer baseFile.Close()
+		overrideFile, err := os.Open(overridePath)
+		Expect(err).To(BeNil())
+		defer overrideFile.Close()
+
+		base := argsini.NewFromFile(*baseFile, "")
+		override := argsini.NewFromFile(*overrideFile, "")
+		layered := argsini.NewLayered(base, override)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		events := layered.Watch(ctx, args.Key{})
+		cancel()
+
+		Eventually(func() bool {
+			_, ok := <-events
+			return ok
+		}, time.Second*2).Should(BeFalse())
+	})
+})
@@ -0,0 +1,487 @@
+package argsini
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// tokenKind identifies the kind of line the tokenizer produced. There is no
+// tokenContinuation kind: a trailing backslash joins the next line into the
+// current logical one during tokenize, so a continued value is never
+// visible as anything other than a single tokenKeyValue whose Value already
+// has the join applied. Surfacing continuations as their own token would
+// force every consumer (WriteTo included) to re-assemble them anyway, so
+// tokenize does that once up front instead.
+type tokenKind int
+
+const (
+	tokenSectionHeader tokenKind = iota
+	tokenKeyValue
+	tokenComment
+	tokenBlankLine
+)
+
+// token is a single logical line read from an ini file, after continuations
+// have been joined back together. A tokenKeyValue token points back at the
+// Pair it produced so WriteTo can re-emit the line with whatever value the
+// Pair currently holds, and can tell a deleted Pair's line apart from a
+// live one.
+type token struct {
+	Kind    tokenKind
+	Section string
+	Key     string
+	Value   string
+	Raw     string
+	Line    int
+	Column  int
+	pair    *Pair
+}
+
+// Pair is a single key/value read from a Document. Unlike args.Pair, a Pair
+// parsed from input carries the source position it was parsed from so
+// callers can produce useful error messages; a Pair added through Set or
+// merged in from an include has Line and Column zero.
+type Pair struct {
+	Key     string
+	Value   string
+	Line    int
+	Column  int
+	Comment string
+
+	// removed marks a Pair that Delete (or a Set collapsing a repeated key
+	// down to one value) has taken out of its section. The Pair's original
+	// token is kept around so WriteTo knows to drop that line rather than
+	// re-emit stale content.
+	removed bool
+}
+
+// section holds the ordered key/value pairs that belong to a single ini
+// section, including the unnamed ("") global section at the top of the file.
+type section struct {
+	name   string
+	order  []string
+	values map[string][]*Pair
+}
+
+func newSection(name string) *section {
+	return &section{
+		name:   name,
+		values: make(map[string][]*Pair),
+	}
+}
+
+func (self *section) add(p *Pair) {
+	if _, ok := self.values[p.Key]; !ok {
+		self.order = append(self.order, p.Key)
+	}
+	self.values[p.Key] = append(self.values[p.Key], p)
+}
+
+func (self *section) set(key, value string) {
+	if existing, ok := self.values[key]; ok {
+		existing[0].Value = value
+		// A repeated key collapses to its first occurrence; mark the rest
+		// removed so WriteTo drops their original lines instead of
+		// re-emitting values that no longer exist in this section.
+		for _, extra := range existing[1:] {
+			extra.removed = true
+		}
+		self.values[key] = existing[:1]
+		return
+	}
+	self.add(&Pair{Key: key, Value: value})
+}
+
+func (self *section) delete(key string) bool {
+	existing, ok := self.values[key]
+	if !ok {
+		return false
+	}
+	for _, p := range existing {
+		p.removed = true
+	}
+	delete(self.values, key)
+	for i, name := range self.order {
+		if name == key {
+			self.order = append(self.order[:i], self.order[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// Document is an ordered, in-memory representation of an ini file. It
+// preserves section and key ordering, comment lines, and blank lines so a
+// file loaded via ParseDocument can be serialized back out with WriteTo.
+// Comment lines, blank lines and section headers come out byte-for-byte;
+// every key/value line is re-emitted as "key = value", so its original
+// spacing and any trailing comment on it are not kept.
+type Document struct {
+	order    []string
+	sections map[string]*section
+	lines    []token
+}
+
+// NewDocument returns an empty Document with only the global section.
+func NewDocument() *Document {
+	doc := &Document{sections: make(map[string]*section)}
+	doc.getOrCreateSection("")
+	return doc
+}
+
+// ParseDocument tokenizes r and assembles the resulting tokens into a
+// Document, preserving insertion order of sections and keys.
+func ParseDocument(r io.Reader) (*Document, error) {
+	tokens, err := tokenize(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "while tokenizing ini document")
+	}
+
+	doc := &Document{sections: make(map[string]*section), lines: tokens}
+	current := doc.getOrCreateSection("")
+
+	for i := range doc.lines {
+		tok := &doc.lines[i]
+		switch tok.Kind {
+		case tokenSectionHeader:
+			current = doc.getOrCreateSection(tok.Section)
+		case tokenKeyValue:
+			p := &Pair{
+				Key:    tok.Key,
+				Value:  tok.Value,
+				Line:   tok.Line,
+				Column: tok.Column,
+			}
+			current.add(p)
+			tok.pair = p
+		}
+	}
+	return doc, nil
+}
+
+func (self *Document) getOrCreateSection(name string) *section {
+	s, ok := self.sections[name]
+	if !ok {
+		s = newSection(name)
+		self.sections[name] = s
+		self.order = append(self.order, name)
+	}
+	return s
+}
+
+// Sections returns the names of every section in the document, in the
+// order they first appeared. The global section is named "".
+func (self *Document) Sections() []string {
+	return append([]string{}, self.order...)
+}
+
+// Get returns every value stored under section/key, in the order they were
+// parsed. A single key may have more than one value if it was repeated.
+func (self *Document) Get(sectionName, key string) []Pair {
+	s, ok := self.sections[sectionName]
+	if !ok {
+		return nil
+	}
+	pairs, ok := s.values[key]
+	if !ok {
+		return nil
+	}
+	results := make([]Pair, 0, len(pairs))
+	for _, p := range pairs {
+		results = append(results, *p)
+	}
+	return results
+}
+
+// List returns every key/value pair defined in section, in parse order.
+func (self *Document) List(sectionName string) []Pair {
+	s, ok := self.sections[sectionName]
+	if !ok {
+		return nil
+	}
+	var results []Pair
+	for _, key := range s.order {
+		for _, p := range s.values[key] {
+			results = append(results, *p)
+		}
+	}
+	return results
+}
+
+// Set replaces all values of section/key with a single value, adding the
+// section and key if they do not already exist.
+func (self *Document) Set(sectionName, key, value string) {
+	self.getOrCreateSection(sectionName).set(key, value)
+}
+
+// Delete removes section/key and reports whether it was present.
+func (self *Document) Delete(sectionName, key string) bool {
+	s, ok := self.sections[sectionName]
+	if !ok {
+		return false
+	}
+	return s.delete(key)
+}
+
+// WriteTo serializes the Document back into ini format, replaying the
+// original token stream so comments, blank lines, and key/section ordering
+// survive unchanged, with Set/Delete edits spliced in: a deleted Pair's
+// line is dropped, an updated Pair's line is rewritten with its new value,
+// and a Pair added after the initial parse is appended to the end of its
+// section.
+func (self *Document) WriteTo(w io.Writer) (int64, error) {
+	buf := &bytes.Buffer{}
+	emitted := make(map[*Pair]bool)
+	visited := map[string]bool{"": true}
+	currentSection := ""
+
+	flushNewPairs := func(name string) {
+		s, ok := self.sections[name]
+		if !ok {
+			return
+		}
+		for _, key := range s.order {
+			for _, p := range s.values[key] {
+				if emitted[p] {
+					continue
+				}
+				buf.WriteString(formatKeyValue(key, p.Value))
+				emitted[p] = true
+			}
+		}
+	}
+
+	for _, tok := range self.lines {
+		switch tok.Kind {
+		case tokenBlankLine, tokenComment:
+			buf.WriteString(tok.Raw)
+			buf.WriteString("\n")
+		case tokenSectionHeader:
+			// Append anything Set added to the section we're leaving
+			// before moving on to the next header.
+			flushNewPairs(currentSection)
+			currentSection = tok.Section
+			visited[currentSection] = true
+			buf.WriteString(tok.Raw)
+			buf.WriteString("\n")
+		case tokenKeyValue:
+			if tok.pair == nil || tok.pair.removed {
+				continue
+			}
+			buf.WriteString(formatKeyValue(tok.Key, tok.pair.Value))
+			emitted[tok.pair] = true
+		}
+	}
+	// Flush new pairs added to whichever section the token stream ended on
+	// (or the global section, for a Document with no lines at all).
+	flushNewPairs(currentSection)
+
+	// Sections created entirely by Set, with no header token of their own,
+	// are appended last in the order they were first used.
+	for _, name := range self.order {
+		if visited[name] {
+			continue
+		}
+		buf.WriteString("[" + name + "]\n")
+		flushNewPairs(name)
+	}
+
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+func formatKeyValue(key, value string) string {
+	return key + " = " + quoteIfNeeded(value) + "\n"
+}
+
+func quoteIfNeeded(value string) string {
+	if value == "" {
+		return value
+	}
+	if strings.ContainsAny(value, "#;") || strings.TrimSpace(value) != value {
+		return "\"" + strings.Replace(value, "\"", "\\\"", -1) + "\""
+	}
+	return value
+}
+
+// tokenize reads r line by line, joining backslash continuations, and
+// classifies each logical line as a section header, key/value pair,
+// comment, or blank line.
+func tokenize(r io.Reader) ([]token, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var tokens []token
+	var pending string
+	pendingLine := 0
+	lineNum := 0
+
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+
+		if pending != "" {
+			line = pending + line
+			pending = ""
+		} else {
+			pendingLine = lineNum
+		}
+
+		if strings.HasSuffix(line, "\\") && !isEscapedBackslash(line) {
+			pending = line[:len(line)-1]
+			continue
+		}
+
+		tok, err := tokenizeLine(line, pendingLine)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, tok)
+	}
+	if pending != "" {
+		tok, err := tokenizeLine(pending, pendingLine)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, tok)
+	}
+	return tokens, scanner.Err()
+}
+
+// isEscapedBackslash reports whether the trailing backslash on line is
+// itself escaped (i.e. "\\\\") and therefore not a continuation marker.
+func isEscapedBackslash(line string) bool {
+	count := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		count++
+	}
+	return count%2 == 0
+}
+
+func tokenizeLine(line string, lineNum int) (token, error) {
+	trimmed := strings.TrimSpace(line)
+	col := len(line) - len(strings.TrimLeft(line, " \t"))
+
+	if trimmed == "" {
+		return token{Kind: tokenBlankLine, Line: lineNum, Column: col, Raw: line}, nil
+	}
+	if strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, ";") {
+		return token{Kind: tokenComment, Line: lineNum, Column: col, Raw: line}, nil
+	}
+	if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+		return token{
+			Kind:    tokenSectionHeader,
+			Section: strings.TrimSpace(trimmed[1 : len(trimmed)-1]),
+			Line:    lineNum,
+			Column:  col,
+			Raw:     line,
+		}, nil
+	}
+
+	key, value, err := splitKeyValue(trimmed)
+	if err != nil {
+		return token{}, errors.Wrapf(err, "at line %d", lineNum)
+	}
+	return token{
+		Kind:   tokenKeyValue,
+		Key:    key,
+		Value:  value,
+		Line:   lineNum,
+		Column: col,
+		Raw:    line,
+	}, nil
+}
+
+// splitKeyValue splits a "key = value" line, honoring quoted values so an
+// `=`, `;` or `#` inside quotes does not terminate the value early.
+func splitKeyValue(line string) (key, value string, err error) {
+	idx := strings.IndexByte(line, '=')
+	if idx == -1 {
+		return "", "", errors.Errorf("expected 'key = value', got %q", line)
+	}
+	key = strings.TrimSpace(line[:idx])
+	rawValue := strings.TrimSpace(line[idx+1:])
+
+	if len(rawValue) >= 2 && (rawValue[0] == '"' || rawValue[0] == '\'') {
+		quote := rawValue[0]
+		end := closingQuoteIndex(rawValue, quote)
+		if end == -1 {
+			return "", "", errors.Errorf("unterminated quoted value %q", rawValue)
+		}
+		unquoted, err := unquote(rawValue[:end+1], quote)
+		if err != nil {
+			return "", "", err
+		}
+		return key, unquoted, nil
+	}
+
+	// Strip trailing unquoted comments.
+	if i := unquotedCommentIndex(rawValue); i != -1 {
+		rawValue = strings.TrimSpace(rawValue[:i])
+	}
+	return key, rawValue, nil
+}
+
+// closingQuoteIndex returns the index of the unescaped quote byte that
+// closes the quoted value starting at value[0], or -1 if value never closes
+// it. Anything after the returned index (e.g. a trailing `; comment`) is not
+// part of the value.
+func closingQuoteIndex(value string, quote byte) int {
+	for i := 1; i < len(value); i++ {
+		if value[i] == '\\' && i+1 < len(value) {
+			i++
+			continue
+		}
+		if value[i] == quote {
+			return i
+		}
+	}
+	return -1
+}
+
+// unquote strips the surrounding quote character from value and resolves
+// the small set of escape sequences ini files rely on (\" \' \\ \n \t).
+func unquote(value string, quote byte) (string, error) {
+	if len(value) < 2 || value[len(value)-1] != quote {
+		return "", errors.Errorf("unterminated quoted value %q", value)
+	}
+	body := value[1 : len(value)-1]
+
+	var out bytes.Buffer
+	for i := 0; i < len(body); i++ {
+		if body[i] == '\\' && i+1 < len(body) {
+			switch body[i+1] {
+			case 'n':
+				out.WriteByte('\n')
+			case 't':
+				out.WriteByte('\t')
+			case '"':
+				out.WriteByte('"')
+			case '\'':
+				out.WriteByte('\'')
+			case '\\':
+				out.WriteByte('\\')
+			default:
+				out.WriteByte(body[i])
+				out.WriteByte(body[i+1])
+			}
+			i++
+			continue
+		}
+		out.WriteByte(body[i])
+	}
+	return out.String(), nil
+}
+
+func unquotedCommentIndex(value string) int {
+	for i := 0; i < len(value); i++ {
+		if value[i] == '#' || value[i] == ';' {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,111 @@
+package argsini_test
+
+import (
+	"bytes"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/thrawn01/argsini"
+)
+
+var _ = Describe("ParseDocument", func() {
+	It("Should preserve section and key ordering", func() {
+		input := strings.NewReader("one=1\n[db]\nhost=localhost\nport=5432\n")
+		doc, err := argsini.ParseDocument(input)
+		Expect(err).To(BeNil())
+		Expect(doc.Sections()).To(Equal([]string{"", "db"}))
+		Expect(doc.List("db")[0].Key).To(Equal("host"))
+		Expect(doc.List("db")[1].Key).To(Equal("port"))
+	})
+
+	It("Should support multiple values for the same key", func() {
+		input := strings.NewReader("[servers]\nhost=one.example.com\nhost=two.example.com\n")
+		doc, err := argsini.ParseDocument(input)
+		Expect(err).To(BeNil())
+		values := doc.Get("servers", "host")
+		Expect(values).To(HaveLen(2))
+		Expect(values[0].Value).To(Equal("one.example.com"))
+		Expect(values[1].Value).To(Equal("two.example.com"))
+	})
+
+	It("Should honor quoted values containing '=', ';' and '#'", func() {
+		input := strings.NewReader(`dsn = "user=bob;password=#secret"` + "\n")
+		doc, err := argsini.ParseDocument(input)
+		Expect(err).To(BeNil())
+		Expect(doc.Get("", "dsn")[0].Value).To(Equal("user=bob;password=#secret"))
+	})
+
+	It("Should allow a trailing comment after a quoted value", func() {
+		input := strings.NewReader(`dsn = "user=bob" ; note` + "\n")
+		doc, err := argsini.ParseDocument(input)
+		Expect(err).To(BeNil())
+		Expect(doc.Get("", "dsn")[0].Value).To(Equal("user=bob"))
+	})
+
+	It("Should join backslash line continuations", func() {
+		input := strings.NewReader("message = hello \\\nworld\n")
+		doc, err := argsini.ParseDocument(input)
+		Expect(err).To(BeNil())
+		Expect(doc.Get("", "message")[0].Value).To(Equal("hello world"))
+	})
+
+	It("Should record the Line a pair was parsed from", func() {
+		input := strings.NewReader("one=1\ntwo=2\n")
+		doc, err := argsini.ParseDocument(input)
+		Expect(err).To(BeNil())
+		Expect(doc.Get("", "one")[0].Line).To(Equal(1))
+		Expect(doc.Get("", "two")[0].Line).To(Equal(2))
+	})
+
+	It("Should round-trip through WriteTo after a Set", func() {
+		doc, err := argsini.ParseDocument(strings.NewReader("[db]\nhost=localhost\n"))
+		Expect(err).To(BeNil())
+		doc.Set("db", "host", "remote")
+
+		var buf bytes.Buffer
+		_, err = doc.WriteTo(&buf)
+		Expect(err).To(BeNil())
+
+		roundTripped, err := argsini.ParseDocument(strings.NewReader(buf.String()))
+		Expect(err).To(BeNil())
+		Expect(roundTripped.Get("db", "host")[0].Value).To(Equal("remote"))
+	})
+
+	It("Should preserve comments and blank lines through WriteTo", func() {
+		// Pairs are re-emitted as "key = value", so the fixture already uses
+		// that spacing to round-trip byte-for-byte.
+		input := "; top of file comment\n\n[db]\n# the primary host\nhost = localhost\n"
+		doc, err := argsini.ParseDocument(strings.NewReader(input))
+		Expect(err).To(BeNil())
+
+		var buf bytes.Buffer
+		_, err = doc.WriteTo(&buf)
+		Expect(err).To(BeNil())
+		Expect(buf.String()).To(Equal(input))
+	})
+
+	It("Should drop a deleted pair's line but keep surrounding comments", func() {
+		input := "[db]\n# the primary host\nhost = localhost\nport = 5432\n"
+		doc, err := argsini.ParseDocument(strings.NewReader(input))
+		Expect(err).To(BeNil())
+		Expect(doc.Delete("db", "host")).To(BeTrue())
+
+		var buf bytes.Buffer
+		_, err = doc.WriteTo(&buf)
+		Expect(err).To(BeNil())
+		Expect(buf.String()).To(Equal("[db]\n# the primary host\nport = 5432\n"))
+	})
+
+	It("Should append a brand new key after a section's existing lines", func() {
+		input := "[db]\n# the primary host\nhost = localhost\n"
+		doc, err := argsini.ParseDocument(strings.NewReader(input))
+		Expect(err).To(BeNil())
+		doc.Set("db", "port", "5432")
+
+		var buf bytes.Buffer
+		_, err = doc.WriteTo(&buf)
+		Expect(err).To(BeNil())
+		Expect(buf.String()).To(Equal("[db]\n# the primary host\nhost = localhost\nport = 5432\n"))
+	})
+})
@@ -0,0 +1,185 @@
+package argsini
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/thrawn01/args"
+)
+
+// DefaultProfileEnvVar is the environment variable NewFromProfile checks at
+// runtime to override the profile passed in at construction, mirroring how
+// AWS_PROFILE overrides the default profile in `~/.aws/config`.
+const DefaultProfileEnvVar = "ARGSINI_PROFILE"
+
+// profileBackend reads an AWS-shared-config style ini file, where
+// `[profile foo]` (or a bare `[foo]`) sections can inherit from a parent via
+// a `source_profile` key. A single file can therefore serve as a
+// multi-environment config store the way `~/.aws/config` does.
+type profileBackend struct {
+	path        string
+	profileName string
+	envVar      string
+
+	mu  sync.RWMutex
+	doc *Document
+}
+
+// NewFromProfile returns a backend that resolves keys against profileName
+// in path, following `source_profile` chains as needed. The active profile
+// can be overridden at runtime by setting ARGSINI_PROFILE.
+func NewFromProfile(path, profileName string) args.Backend {
+	return &profileBackend{
+		path:        path,
+		profileName: profileName,
+		envVar:      DefaultProfileEnvVar,
+	}
+}
+
+// ActiveProfile returns the profile currently in effect: the ARGSINI_PROFILE
+// environment variable if set, otherwise the profile passed to
+// NewFromProfile.
+func (self *profileBackend) ActiveProfile() string {
+	if name := os.Getenv(self.envVar); name != "" {
+		return name
+	}
+	return self.profileName
+}
+
+func (self *profileBackend) ensureLoaded() error {
+	self.mu.RLock()
+	loaded := self.doc != nil
+	self.mu.RUnlock()
+	if loaded {
+		return nil
+	}
+
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if self.doc != nil {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(self.path)
+	if err != nil {
+		return errors.Wrapf(err, "while reading ini '%s'", self.path)
+	}
+	self.doc, err = ParseDocument(bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrapf(err, "while parsing ini '%s'", self.path)
+	}
+	return nil
+}
+
+// sectionFor returns the section name that holds profile's settings,
+// preferring the AWS-style `[profile <name>]` form and falling back to a
+// bare `[<name>]` section.
+func (self *profileBackend) sectionFor(profile string) string {
+	prefixed := "profile " + profile
+	for _, name := range self.doc.Sections() {
+		if name == prefixed {
+			return prefixed
+		}
+	}
+	return profile
+}
+
+// Get resolves key.Name by looking in the active profile's section, then
+// following source_profile up the chain until the key is found or the
+// chain ends. Cycles in source_profile are detected and reported as errors.
+func (self *profileBackend) Get(ctx context.Context, key args.Key) (args.Pair, error) {
+	if err := self.ensureLoaded(); err != nil {
+		return args.Pair{}, err
+	}
+
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+
+	profile := self.ActiveProfile()
+	visited := make(map[string]bool)
+	for profile != "" {
+		if visited[profile] {
+			return args.Pair{}, errors.Errorf("source_profile cycle detected at '%s'", profile)
+		}
+		visited[profile] = true
+
+		sectionName := self.sectionFor(profile)
+		if pairs := self.doc.Get(sectionName, key.Name); len(pairs) > 0 {
+			return args.Pair{Key: key, Value: []byte(pairs[0].Value)}, nil
+		}
+
+		parent := self.doc.Get(sectionName, "source_profile")
+		if len(parent) == 0 {
+			break
+		}
+		profile = parent[0].Value
+	}
+	return args.Pair{Key: key}, nil
+}
+
+// List returns the effective merged view of the active profile: its own
+// keys, plus any key inherited from a source_profile ancestor that it does
+// not itself define.
+func (self *profileBackend) List(ctx context.Context, key args.Key) ([]args.Pair, error) {
+	if err := self.ensureLoaded(); err != nil {
+		return nil, err
+	}
+
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+
+	var results []args.Pair
+	seen := make(map[string]bool)
+
+	profile := self.ActiveProfile()
+	visited := make(map[string]bool)
+	for profile != "" {
+		if visited[profile] {
+			return nil, errors.Errorf("source_profile cycle detected at '%s'", profile)
+		}
+		visited[profile] = true
+
+		sectionName := self.sectionFor(profile)
+		for _, pair := range self.doc.List(sectionName) {
+			if pair.Key == "source_profile" || seen[pair.Key] {
+				continue
+			}
+			seen[pair.Key] = true
+			results = append(results, args.Pair{
+				Key:   args.Key{Name: pair.Key},
+				Value: []byte(pair.Value),
+			})
+		}
+
+		parent := self.doc.Get(sectionName, "source_profile")
+		if len(parent) == 0 {
+			break
+		}
+		profile = parent[0].Value
+	}
+	return results, nil
+}
+
+// Set is not supported; profile chains are meant to be edited by hand.
+func (self *profileBackend) Set(ctx context.Context, key args.Key, value []byte) error {
+	return errors.New("Not Implemented")
+}
+
+// Watch is not supported for profile-chained configs.
+func (self *profileBackend) Watch(ctx context.Context, key args.Key) <-chan *args.ChangeEvent {
+	changeChan := make(chan *args.ChangeEvent)
+	close(changeChan)
+	return changeChan
+}
+
+// GetRootKey returns the active profile name.
+func (self *profileBackend) GetRootKey() string {
+	return self.ActiveProfile()
+}
+
+// Close is a no-op; profileBackend holds no background resources.
+func (self *profileBackend) Close() {}
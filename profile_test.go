@@ -0,0 +1,86 @@
+package argsini_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/thrawn01/args"
+	"github.com/thrawn01/argsini"
+)
+
+var _ = Describe("NewFromProfile", func() {
+	var path string
+
+	BeforeEach(func() {
+		dir, err := ioutil.TempDir("", "argsini-profile")
+		Expect(err).To(BeNil())
+		path = filepath.Join(dir, "config")
+		Expect(ioutil.WriteFile(path, []byte(strings.TrimSpace(`
+[profile base]
+region = us-east-1
+output = json
+
+[profile dev]
+source_profile = base
+region = us-west-2
+`)+"\n"), 0644)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(filepath.Dir(path))
+	})
+
+	It("Should resolve a key from the profile itself before its parent", func() {
+		backend := argsini.NewFromProfile(path, "dev")
+		pair, err := backend.Get(context.Background(), args.Key{Name: "region"})
+		Expect(err).To(BeNil())
+		Expect(string(pair.Value)).To(Equal("us-west-2"))
+	})
+
+	It("Should fall back to source_profile for keys the profile doesn't define", func() {
+		backend := argsini.NewFromProfile(path, "dev")
+		pair, err := backend.Get(context.Background(), args.Key{Name: "output"})
+		Expect(err).To(BeNil())
+		Expect(string(pair.Value)).To(Equal("json"))
+	})
+
+	It("Should let ARGSINI_PROFILE override the constructor profile", func() {
+		os.Setenv("ARGSINI_PROFILE", "base")
+		defer os.Unsetenv("ARGSINI_PROFILE")
+
+		backend := argsini.NewFromProfile(path, "dev")
+		pair, err := backend.Get(context.Background(), args.Key{Name: "region"})
+		Expect(err).To(BeNil())
+		Expect(string(pair.Value)).To(Equal("us-east-1"))
+	})
+
+	It("Should expose the active profile via ActiveProfile()", func() {
+		backend := argsini.NewFromProfile(path, "dev")
+		active := backend.(interface{ ActiveProfile() string })
+		Expect(active.ActiveProfile()).To(Equal("dev"))
+
+		os.Setenv("ARGSINI_PROFILE", "base")
+		defer os.Unsetenv("ARGSINI_PROFILE")
+		Expect(active.ActiveProfile()).To(Equal("base"))
+	})
+
+	It("Should return the merged effective view from List", func() {
+		backend := argsini.NewFromProfile(path, "dev")
+		pairs, err := backend.List(context.Background(), args.Key{})
+		Expect(err).To(BeNil())
+
+		values := map[string]string{}
+		for _, pair := range pairs {
+			values[pair.Key.Name] = string(pair.Value)
+		}
+		Expect(values).To(Equal(map[string]string{
+			"region": "us-west-2",
+			"output": "json",
+		}))
+	})
+})
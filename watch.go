@@ -0,0 +1,307 @@
+package argsini
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/fsnotify.v1"
+)
+
+// fileEvent describes a single observed change to a watched path. Op is
+// derived from comparing the path's mtime/size with the last snapshot, not
+// copied from fsnotify: fsnotify.Create for a newly matched file,
+// fsnotify.Write for a changed mtime or size, and fsnotify.Remove for a file
+// that no longer matches, whether fsnotify or polling noticed the change.
+type fileEvent struct {
+	Name string
+	Op   fsnotify.Op
+	Err  error
+}
+
+// fileState is the last known mtime/size of a file the Watcher is tracking,
+// used to dedupe editor rename+write storms and to detect changes that
+// fsnotify never reported (NFS mounts, some container filesystems).
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// Watcher watches one or more files or glob patterns for changes. It watches
+// the containing directories rather than the files themselves so that an
+// atomic rename() (as used by most editors and by Kubernetes ConfigMap
+// symlink swaps) is caught reliably, and it falls back to pure polling when
+// fsnotify is unavailable.
+//
+// Watcher has no dependency on INIBackend so other backends in the args
+// ecosystem can reuse it.
+type Watcher struct {
+	patterns     []string
+	refreshEvery time.Duration
+	polling      bool
+
+	mu    sync.Mutex
+	state map[string]fileState
+
+	fsWatch *fsnotify.Watcher
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewWatcher returns a Watcher for the given file paths or glob patterns.
+// refreshEvery controls how often the watcher re-scans patterns for new or
+// removed matches and re-checks mtimes, independent of what fsnotify reports.
+func NewWatcher(patterns []string, refreshEvery time.Duration) *Watcher {
+	return &Watcher{
+		patterns:     patterns,
+		refreshEvery: refreshEvery,
+		state:        make(map[string]fileState),
+		done:         make(chan struct{}),
+	}
+}
+
+// Watch begins watching and returns a channel of file events. The channel is
+// closed when ctx is canceled or Close is called.
+func (self *Watcher) Watch(ctx context.Context) (<-chan fileEvent, error) {
+	result := make(chan fileEvent)
+
+	matches, err := self.expandPatterns()
+	if err != nil {
+		return nil, err
+	}
+	self.snapshot(matches)
+
+	fsWatch, err := fsnotify.NewWatcher()
+	if err != nil {
+		// Platform does not support fsnotify, or we're out of inotify
+		// watches (ENOSPC) -- fall back to pure polling.
+		self.polling = true
+	} else {
+		self.fsWatch = fsWatch
+		for _, dir := range self.watchedDirs(matches) {
+			if err := fsWatch.Add(dir); err != nil {
+				// Treat a failure to add any single directory as reason
+				// enough to fall back to polling entirely, rather than
+				// silently watching a partial set of directories.
+				fsWatch.Close()
+				self.fsWatch = nil
+				self.polling = true
+				break
+			}
+		}
+	}
+
+	var isRunning sync.WaitGroup
+	isRunning.Add(1)
+
+	self.wg.Add(1)
+	go func() {
+		defer self.wg.Done()
+		defer close(result)
+
+		tick := time.NewTicker(self.refreshEvery)
+		defer tick.Stop()
+
+		var fsEvents <-chan fsnotify.Event
+		var fsErrors <-chan error
+		if self.fsWatch != nil {
+			fsEvents = self.fsWatch.Events
+			fsErrors = self.fsWatch.Errors
+		}
+
+		isRunning.Done() // Notify we are running
+		for {
+			select {
+			case event, ok := <-fsEvents:
+				if !ok {
+					fsEvents = nil
+					continue
+				}
+				self.handleFSEvent(event, result)
+			case err, ok := <-fsErrors:
+				if !ok {
+					fsErrors = nil
+					continue
+				}
+				result <- fileEvent{Err: errors.Wrap(err, "from fsnotify watcher")}
+			case <-tick.C:
+				self.refresh(result)
+			case <-self.done:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	isRunning.Wait()
+	return result, nil
+}
+
+// Close stops the watcher goroutine and releases the underlying fsnotify
+// watcher, if any.
+func (self *Watcher) Close() {
+	select {
+	case <-self.done:
+		// already closed
+	default:
+		close(self.done)
+	}
+	if self.fsWatch != nil {
+		self.fsWatch.Close()
+	}
+	self.wg.Wait()
+}
+
+// handleFSEvent re-checks the file named by event and lets diffAndEmit decide
+// whether a synthetic event is warranted, which collapses the multiple
+// fsnotify events a single save can generate (e.g. VIM's rename-then-write)
+// into at most one change per tracked file.
+func (self *Watcher) handleFSEvent(event fsnotify.Event, result chan<- fileEvent) {
+	if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 && self.fsWatch != nil {
+		// The containing directory is still watched, so a ConfigMap style
+		// symlink swap or editor rename is picked up here; re-add the path
+		// in case fsnotify dropped the watch on it.
+		self.fsWatch.Add(event.Name)
+	}
+
+	matches, err := self.expandPatterns()
+	if err != nil {
+		result <- fileEvent{Err: err}
+		return
+	}
+	self.diffAndEmit(matches, result)
+}
+
+// refresh re-scans all patterns for added, removed, or changed files. It
+// runs on every refreshEvery tick so changes are caught even when fsnotify
+// misses them entirely (NFS mounts, some container filesystems).
+func (self *Watcher) refresh(result chan<- fileEvent) {
+	matches, err := self.expandPatterns()
+	if err != nil {
+		result <- fileEvent{Err: err}
+		return
+	}
+
+	// Pick up any newly created files/directories that match our patterns.
+	if self.fsWatch != nil {
+		for _, dir := range self.watchedDirs(matches) {
+			self.fsWatch.Add(dir)
+		}
+	}
+
+	self.diffAndEmit(matches, result)
+}
+
+// diffAndEmit compares matches against the last known snapshot and emits one
+// fileEvent per added, removed, or modified file.
+func (self *Watcher) diffAndEmit(matches []string, result chan<- fileEvent) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	seen := make(map[string]bool, len(matches))
+	for _, path := range matches {
+		seen[path] = true
+
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		next := fileState{modTime: info.ModTime(), size: info.Size()}
+		prev, existed := self.state[path]
+		self.state[path] = next
+
+		if !existed {
+			result <- fileEvent{Name: path, Op: fsnotify.Create}
+			continue
+		}
+		if next.modTime != prev.modTime || next.size != prev.size {
+			result <- fileEvent{Name: path, Op: fsnotify.Write}
+		}
+	}
+
+	for path := range self.state {
+		if !seen[path] {
+			delete(self.state, path)
+			result <- fileEvent{Name: path, Op: fsnotify.Remove}
+		}
+	}
+}
+
+// snapshot records the current mtime/size of matches without emitting any
+// events, used to establish the initial baseline before watching begins.
+func (self *Watcher) snapshot(matches []string) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	for _, path := range matches {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		self.state[path] = fileState{modTime: info.ModTime(), size: info.Size()}
+	}
+}
+
+// expandPatterns resolves self.patterns (plain paths or glob patterns) to a
+// deduplicated list of matching file paths.
+func (self *Watcher) expandPatterns() ([]string, error) {
+	seen := make(map[string]bool)
+	var results []string
+
+	for _, pattern := range self.patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, errors.Wrapf(err, "while expanding pattern '%s'", pattern)
+		}
+		if matches == nil {
+			// Not a glob, or a glob with no current matches -- still track
+			// the literal path so we notice it once it's created.
+			matches = []string{pattern}
+		}
+		for _, match := range matches {
+			if !seen[match] {
+				seen[match] = true
+				results = append(results, match)
+			}
+		}
+	}
+	return results, nil
+}
+
+// watchedDirs returns the deduplicated set of directories containing
+// matches, which is what we actually register with fsnotify.
+func (self *Watcher) watchedDirs(matches []string) []string {
+	seen := make(map[string]bool)
+	var dirs []string
+	for _, path := range matches {
+		dir := filepath.Dir(path)
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// watchFile preserves the single-file watch API the rest of argsini was
+// built against, implemented on top of Watcher.
+func watchFile(ctx context.Context, path string, interval time.Duration) (chan fileEvent, error) {
+	watcher := NewWatcher([]string{path}, interval)
+	events, err := watcher.Watch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(chan fileEvent)
+	go func() {
+		defer close(result)
+		for event := range events {
+			result <- event
+		}
+	}()
+	return result, nil
+}
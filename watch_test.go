@@ -0,0 +1,56 @@
+package argsini_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/thrawn01/argsini"
+)
+
+var _ = Describe("Watcher", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "argsini-watch")
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("Should emit a change event when a watched file is modified", func() {
+		path := filepath.Join(dir, "app.ini")
+		Expect(ioutil.WriteFile(path, []byte("one=1\n"), 0644)).To(Succeed())
+
+		watcher := argsini.NewWatcher([]string{path}, 50*time.Millisecond)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		events, err := watcher.Watch(ctx)
+		Expect(err).To(BeNil())
+
+		Expect(ioutil.WriteFile(path, []byte("one=2\n"), 0644)).To(Succeed())
+
+		Eventually(events, "2s").Should(Receive())
+	})
+
+	It("Should pick up new files matching a glob pattern", func() {
+		watcher := argsini.NewWatcher([]string{filepath.Join(dir, "*.ini")}, 50*time.Millisecond)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		events, err := watcher.Watch(ctx)
+		Expect(err).To(BeNil())
+
+		Expect(ioutil.WriteFile(filepath.Join(dir, "new.ini"), []byte("one=1\n"), 0644)).To(Succeed())
+
+		Eventually(events, "2s").Should(Receive())
+	})
+})